@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
+	"runtime"
 	"strings"
+	"time"
 )
 
 var VERSION = "0.0.1"
@@ -29,11 +32,24 @@ type UserArgs struct {
 var commands = []Command{
 	{version, "version", "Show the version of briefcase", "briefcase version"},
 	{info, "info", "Show information about the temp directory used by briefcase", "briefcase info"},
-	{set, "set", "Set a briefcase variable", "briefcase set <variable> <value>"},
-	{get, "get", "Get a briefcase variable", "briefcase get <variable>"},
-	{purge, "purge", "Purge briefcase data. Optionally allows [force] param to prevent prompting.", "briefcase purge [force]"},
+	{set, "set", "Set a briefcase variable. Allows --ttl <duration> and --tag <a,b,c>.", "briefcase set <variable> [--ttl 24h] [--tag a,b] <value>"},
+	{get, "get", "Get a briefcase variable. Refuses (and removes) expired entries.", "briefcase get <variable>"},
+	{purge, "purge", "Purge briefcase data, optionally scoped to a namespace. Allows a trailing 'force' param to prevent prompting.", "briefcase purge [namespace] [force]"},
 	{remove, "remove", "Remove a briefcase variable", "briefcase remove <variable>"},
-	{list, "list", "List briefcase entries", "briefcase list"},
+	{list, "list", "List briefcase entries, optionally under a namespace, glob ('work/*/token'), recursively with -r, filtered by --tag, or with age/TTL/tags via -l", "briefcase list [-r] [-l] [--tag foo] [namespace|glob]"},
+	{rekey, "rekey", "Re-encrypt all entries under a new passphrase", "briefcase rekey"},
+	{export, "export", "Export all briefcase entries as JSON", "briefcase export"},
+	{importCmd, "import", "Import briefcase entries from JSON", "briefcase import"},
+	{env, "env", "Print export statements for briefcase entries, for eval \"$(briefcase env)\"", "briefcase env [--posix|--fish|--powershell] [--only a,b] [--prefix <namespace>] [prefix|glob]"},
+	{execCmd, "exec", "Run a command with briefcase entries loaded into its environment", "briefcase exec -- <command> [args...]"},
+	{gc, "gc", "Remove entries whose TTL has expired", "briefcase gc"},
+}
+
+// entry is the JSON representation of a single briefcase entry used by
+// export and import.
+type entry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 func main() {
@@ -68,23 +84,60 @@ func main() {
 
 // utility functions
 
-// getTempDir returns the base directory used by the briefcase program to store data.
-// This is intended to be a 'temp' directory, but could be anything. This function
-// avoids using os.TempDir to allow reporting what environment variable was used.
+// getTempDir returns the base directory used by the briefcase program to
+// store data. BRIEFCASE_DIR always wins if set. Otherwise, BRIEFCASE_PERSISTENT
+// switches to a directory that survives reboots (via os.UserCacheDir);
+// by default briefcase uses a true, OS-appropriate temp directory, which
+// on macOS in particular is wiped on every reboot.
 func getTempDir() TempDir {
-	var envVars = []string{
-		"BRIEFCASE_DIR",
-		"TEMP",
-		"TMPDIR",
+	if dir := os.Getenv("BRIEFCASE_DIR"); dir != "" {
+		return TempDir{dir, "BRIEFCASE_DIR"}
+	}
+
+	if os.Getenv("BRIEFCASE_PERSISTENT") != "" {
+		return getPersistentDir()
 	}
 
-	for _, envVar := range envVars {
-		if dir := os.Getenv(envVar); dir != "" {
-			return TempDir{dir, envVar}
+	return resolveTempDir(runtime.GOOS)
+}
+
+// resolveTempDir mirrors the precedence os.TempDir uses on each OS, but
+// (unlike os.TempDir) reports which source was used. It takes goos as a
+// parameter, rather than reading runtime.GOOS directly, so tests can
+// exercise every OS's precedence regardless of the OS actually running
+// the test.
+func resolveTempDir(goos string) TempDir {
+	if goos == "windows" {
+		for _, envVar := range []string{"TMP", "TEMP", "USERPROFILE"} {
+			if dir := os.Getenv(envVar); dir != "" {
+				return TempDir{dir, envVar}
+			}
 		}
+		return TempDir{os.TempDir(), "os.TempDir()"}
 	}
 
-	return TempDir{"/tmp", "N/A"}
+	// Darwin and Linux (and everything else os.TempDir supports): TMPDIR,
+	// then whatever os.TempDir falls back to ("/tmp" unless overridden).
+	if dir := os.Getenv("TMPDIR"); dir != "" {
+		return TempDir{dir, "TMPDIR"}
+	}
+	return TempDir{os.TempDir(), "os.TempDir()"}
+}
+
+// getPersistentDir returns a directory that survives reboots, for
+// BRIEFCASE_PERSISTENT. os.UserCacheDir is tried first since briefcase
+// data is disposable, cache-like data; os.UserConfigDir is a fallback for
+// platforms/environments where no cache directory is configured.
+func getPersistentDir() TempDir {
+	if dir, err := os.UserCacheDir(); err == nil {
+		return TempDir{dir, "os.UserCacheDir()"}
+	}
+
+	if dir, err := os.UserConfigDir(); err == nil {
+		return TempDir{dir, "os.UserConfigDir()"}
+	}
+
+	return resolveTempDir(runtime.GOOS)
 }
 
 // getBriefcaseDirName determines the directory that will be created and used insited
@@ -137,66 +190,130 @@ func info(_ UserArgs) bool {
 	return true
 }
 
-// set will store user provided data into the briefcase directory.
+// set will store user provided data into the briefcase directory, along
+// with its metadata: an optional --ttl expiry, optional --tag list, and a
+// content hash used by get to detect tampering or staleness.
 func set(args UserArgs) bool {
-	briefcase := getBriefcaseDir()
-	if args.name == "" || args.value == "" {
+	if args.name == "" {
 		fmt.Println("Missing argument for 'set'")
 		return false
 	}
 
-	err := os.MkdirAll(briefcase, 0700)
-	if err != nil {
-		fmt.Println("Error: " + err.Error())
+	flags, value := extractLeadingFlags(args.value, []string{"--ttl", "--tag"})
+	ttlRaw, hasTTL := flags["--ttl"]
+	tagsRaw, hasTags := flags["--tag"]
+
+	if value == "" {
+		fmt.Println("Missing argument for 'set'")
+		return false
 	}
 
-	err = os.WriteFile(path.Join(briefcase, args.name), []byte(args.value), 0644)
-	if err != nil {
-		fmt.Println("ERROR: failed to write file - " + err.Error())
+	name := args.name
+
+	var ttl time.Duration
+	if hasTTL {
+		var err error
+		ttl, err = time.ParseDuration(ttlRaw)
+		if err != nil {
+			fmt.Println("ERROR: invalid --ttl value - " + err.Error())
+			return false
+		}
+	}
+
+	var tags []string
+	if hasTags && tagsRaw != "" {
+		tags = strings.Split(tagsRaw, ",")
+	}
+
+	if err := getStore().Set(name, value); err != nil {
+		fmt.Println("ERROR: failed to write entry - " + err.Error())
+		return false
+	}
+
+	if err := recordSet(getBriefcaseDir(), name, value, ttl, tags); err != nil {
+		fmt.Println("ERROR: failed to write entry metadata - " + err.Error())
 		return false
 	}
 
 	return true
 }
 
-// get retrieves data from the briefcase directory.
+// get retrieves data from the briefcase directory, refusing (and removing)
+// entries whose TTL has expired, and refusing entries whose content no
+// longer matches the hash recorded at set time so tampered or corrupt
+// values are surfaced rather than silently returned.
 func get(args UserArgs) bool {
-	briefcase := getBriefcaseDir()
 	if args.name == "" {
 		fmt.Println("ERROR: No briefcase entry specified.")
 		return false
 	}
 
-	data, err := os.ReadFile(path.Join(briefcase, args.name))
+	root := getBriefcaseDir()
+	meta, hasMeta, metaErr := lookupMeta(root, args.name)
+	if metaErr == nil && hasMeta && meta.expired() {
+		_ = getStore().Remove(args.name)
+		_ = removeMeta(root, args.name)
+		fmt.Println("ERROR: entry has expired")
+		return false
+	}
+
+	data, err := getStore().Get(args.name)
 	if err != nil {
-		fmt.Println("ERROR: failed to read file - " + err.Error())
+		fmt.Println("ERROR: failed to read entry - " + err.Error())
 		return false
 	}
 
-	os.Stdout.Write(data)
+	if metaErr == nil && hasMeta && meta.SHA256 != "" && hashValue(data) != meta.SHA256 {
+		fmt.Println("ERROR: entry failed integrity check - value does not match its recorded hash (tampered or corrupt)")
+		return false
+	}
+
+	_ = recordAccess(root, args.name)
+
+	fmt.Print(data)
 	return true
 }
 
-// purge removes all briefcase data.
+// purge removes all briefcase data, or just a namespace if one is given.
 // prompts user for confirmation if force is not provided.
 func purge(args UserArgs) bool {
+	namespace := args.name
+	forced := args.value == "force"
+	if namespace == "force" {
+		namespace = ""
+		forced = true
+	}
+
+	target := "all briefcase data"
+	if namespace != "" {
+		target = "the briefcase namespace '" + namespace + "'"
+	}
+
 	var confirm string
-	if args.name == "force" {
+	if forced {
 		confirm = "y"
 	} else {
-		fmt.Println("Are you sure you want to delete all briefcase data? (y/n)")
+		fmt.Println("Are you sure you want to delete " + target + "? (y/n)")
 		fmt.Scan(&confirm)
 	}
 
 	if confirm != "y" {
 		fmt.Println("Exiting without deleting data")
 	} else {
-		briefcase := getBriefcaseDir()
-		err := os.RemoveAll(briefcase)
+		err := getStore().Purge(namespace)
 		if err != nil {
 			fmt.Println("ERROR: Failed to remove briefcase directory - " + err.Error())
 			return false
 		}
+
+		// A full purge (namespace == "") already removed the metadata
+		// index along with everything else.
+		if namespace != "" {
+			if err := purgeMeta(getBriefcaseDir(), namespace); err != nil {
+				fmt.Println("ERROR: Failed to remove briefcase metadata - " + err.Error())
+				return false
+			}
+		}
 	}
 
 	return true
@@ -204,32 +321,193 @@ func purge(args UserArgs) bool {
 
 // remove deletes the data for the given breifcase entry.
 func remove(args UserArgs) bool {
-	briefcase := getBriefcaseDir()
 	if args.name == "" {
 		fmt.Println("ERROR: No briefcase entry specified.")
 		return false
 	}
 
-	err := os.Remove(path.Join(briefcase, args.name))
+	err := getStore().Remove(args.name)
 	if err != nil {
 		fmt.Println("ERROR: Failed to remove file -  " + err.Error())
 	}
 
+	_ = removeMeta(getBriefcaseDir(), args.name)
+
 	return true
 }
 
-// list dumps the full list of briefcase entries.
-func list(_ UserArgs) bool {
-	briefcase := getBriefcaseDir()
-	files, err := os.ReadDir(briefcase)
+// list dumps briefcase entries, optionally scoped to a namespace prefix
+// or glob pattern. This never decrypts entry values - it only reports the
+// (plaintext) entry names, plus metadata if requested.
+//
+// Flags (in any order): -r to recurse into namespaces, -l for a long
+// listing with age/TTL/tags columns, --tag <tag> to filter to entries
+// carrying that tag.
+func list(args UserArgs) bool {
+	tokens := tokenizeArgs(args)
+	recursive, tokens := extractBoolFlag(tokens, "-r")
+	longFormat, tokens := extractBoolFlag(tokens, "-l")
+	tag, tokens, filterByTag := extractValueFlag(tokens, "--tag")
+	prefix := strings.Join(tokens, " ")
+
+	names, err := getStore().List(prefix, recursive)
 	if err != nil {
 		// If there's an error, it's because the briefcase dir doesn't exist.
 		// simply list nothing.
 		return true
 	}
 
-	for _, file := range files {
-		fmt.Println(file.Name())
+	index, _ := loadIndex(getBriefcaseDir())
+
+	for _, name := range names {
+		meta, hasMeta := index[name]
+		if filterByTag && (!hasMeta || !meta.hasTag(tag)) {
+			continue
+		}
+
+		if !longFormat {
+			fmt.Println(name)
+			continue
+		}
+
+		fmt.Println(name + "\t" + formatListMeta(meta, hasMeta))
+	}
+	return true
+}
+
+// formatListMeta renders the age/TTL/tags columns for list -l. Entries
+// with no recorded metadata (e.g. namespace directories) show dashes.
+func formatListMeta(meta entryMeta, hasMeta bool) string {
+	if !hasMeta {
+		return "-\t-\t-"
+	}
+
+	age := time.Since(meta.CreatedAt).Round(time.Second)
+
+	ttl := "-"
+	if meta.ExpiresAt != nil {
+		remaining := time.Until(*meta.ExpiresAt).Round(time.Second)
+		if remaining < 0 {
+			remaining = 0
+		}
+		ttl = remaining.String()
 	}
+
+	tags := "-"
+	if len(meta.Tags) > 0 {
+		tags = strings.Join(meta.Tags, ",")
+	}
+
+	return age.String() + "\t" + ttl + "\t" + tags
+}
+
+// gc removes every entry whose TTL has expired.
+func gc(_ UserArgs) bool {
+	root := getBriefcaseDir()
+	index, err := loadIndex(root)
+	if err != nil {
+		fmt.Println("ERROR: failed to read metadata - " + err.Error())
+		return false
+	}
+
+	store := getStore()
+	removed := 0
+	for name, meta := range index {
+		if !meta.expired() {
+			continue
+		}
+
+		if err := store.Remove(name); err != nil && !os.IsNotExist(err) {
+			fmt.Println("ERROR: failed to remove expired entry " + name + " - " + err.Error())
+			return false
+		}
+
+		delete(index, name)
+		removed++
+	}
+
+	if err := saveIndex(root, index); err != nil {
+		fmt.Println("ERROR: failed to update metadata - " + err.Error())
+		return false
+	}
+
+	fmt.Println("Removed", removed, "expired entries")
+	return true
+}
+
+// rekey re-encrypts every entry under a newly prompted passphrase. Only
+// supported when the active store is an EncryptedStore.
+func rekey(_ UserArgs) bool {
+	store, ok := getStore().(*EncryptedStore)
+	if !ok {
+		fmt.Println("ERROR: rekey requires the encrypted store (unset BRIEFCASE_PLAINTEXT)")
+		return false
+	}
+
+	fmt.Print("New briefcase passphrase: ")
+	var newPassphrase string
+	if _, err := fmt.Scanln(&newPassphrase); err != nil {
+		fmt.Println("ERROR: failed to read new passphrase - " + err.Error())
+		return false
+	}
+
+	if err := store.rekeyed(newPassphrase); err != nil {
+		fmt.Println("ERROR: rekey failed - " + err.Error())
+		return false
+	}
+
+	fmt.Println(persistPassphrase(newPassphrase))
+
+	return true
+}
+
+// export prints every briefcase entry, decrypted, as a JSON array so it can
+// be piped to a file for backup or fed into `briefcase import` elsewhere.
+func export(_ UserArgs) bool {
+	store := getStore()
+	names, err := store.List("", true)
+	if err != nil {
+		fmt.Println("ERROR: failed to list entries - " + err.Error())
+		return false
+	}
+
+	entries := make([]entry, 0, len(names))
+	for _, name := range names {
+		value, err := store.Get(name)
+		if err != nil {
+			fmt.Println("ERROR: failed to read entry " + name + " - " + err.Error())
+			return false
+		}
+		entries = append(entries, entry{Name: name, Value: value})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		fmt.Println("ERROR: failed to encode entries - " + err.Error())
+		return false
+	}
+
+	fmt.Println(string(data))
+	return true
+}
+
+// importCmd reads a JSON array of entries (in the format produced by
+// export) from stdin and writes each one into the active store.
+func importCmd(_ UserArgs) bool {
+	var entries []entry
+	decoder := json.NewDecoder(os.Stdin)
+	if err := decoder.Decode(&entries); err != nil {
+		fmt.Println("ERROR: failed to decode entries - " + err.Error())
+		return false
+	}
+
+	store := getStore()
+	for _, e := range entries {
+		if err := store.Set(e.Name, e.Value); err != nil {
+			fmt.Println("ERROR: failed to write entry " + e.Name + " - " + err.Error())
+			return false
+		}
+	}
+
 	return true
 }
@@ -24,6 +24,7 @@ func setVarsForTest(t *testing.T) string {
 
 	t.Setenv("BRIEFCASE_DIR", dir)
 	t.Setenv("BRIEFCASE_DIRNAME", DIRNAME)
+	t.Setenv("BRIEFCASE_PASSPHRASE", "test-passphrase")
 	return dir
 }
 
@@ -84,6 +85,22 @@ func TestSetGet(t *testing.T) {
 	}
 }
 
+func TestSetPreservesInternalWhitespace(t *testing.T) {
+	setVarsForTest(t)
+	args := UserArgs{"MyVar", "a   b\tc"}
+	if !set(args) {
+		t.Fatal("expected set to succeed")
+	}
+
+	out := stealStdOut(t)
+	get(UserArgs{"MyVar", ""})
+	restoreStdOut(&out)
+	data := getStdOut(&out)
+	if data != args.value {
+		t.Fatal("expected internal whitespace to be preserved, got ", data)
+	}
+}
+
 func TestSetListPurge(t *testing.T) {
 	setVarsForTest(t)
 	args := UserArgs{"MyVar", "data"}
@@ -93,17 +110,16 @@ func TestSetListPurge(t *testing.T) {
 	args = UserArgs{"MyVar3", "data3"}
 	set(args)
 	out := stealStdOut(t)
-	list(args)
+	list(UserArgs{})
 	restoreStdOut(&out)
 	data := getStdOut(&out)
 	if data != "MyVar\nMyVar2\nMyVar3\n" {
 		t.Fatal("Not all variables present, got: ", data)
 	}
 
-	args = UserArgs{"force", ""}
-	purge(args)
+	purge(UserArgs{"force", ""})
 	out = stealStdOut(t)
-	list(args)
+	list(UserArgs{})
 	restoreStdOut(&out)
 	data = getStdOut(&out)
 	if data != "" {
@@ -0,0 +1,99 @@
+package main
+
+import "strings"
+
+// tokenizeArgs turns a UserArgs back into individual whitespace-delimited
+// tokens, undoing main's "join the rest with spaces" flattening so
+// commands that take more than a single name/value pair (e.g. list's
+// -r/-l/--tag, env's --only/--prefix) can find their flags regardless of
+// where the caller put them.
+func tokenizeArgs(args UserArgs) []string {
+	var tokens []string
+	if args.name != "" {
+		tokens = append(tokens, args.name)
+	}
+	if args.value != "" {
+		tokens = append(tokens, strings.Fields(args.value)...)
+	}
+	return tokens
+}
+
+// extractValueFlag removes the first occurrence of "flag <value>" from
+// tokens, returning the value found (if any) alongside the remaining
+// tokens.
+func extractValueFlag(tokens []string, flag string) (value string, remaining []string, found bool) {
+	for i, t := range tokens {
+		if t != flag {
+			continue
+		}
+
+		remaining = append(append([]string{}, tokens[:i]...), tokens[i+1:]...)
+		if i < len(remaining) {
+			value = remaining[i]
+			remaining = append(remaining[:i], remaining[i+1:]...)
+		}
+		return value, remaining, true
+	}
+
+	return "", tokens, false
+}
+
+// extractBoolFlag removes the first occurrence of flag from tokens,
+// reporting whether it was present.
+func extractBoolFlag(tokens []string, flag string) (found bool, remaining []string) {
+	for i, t := range tokens {
+		if t == flag {
+			return true, append(append([]string{}, tokens[:i]...), tokens[i+1:]...)
+		}
+	}
+	return false, tokens
+}
+
+// extractLeadingFlags consumes "flag value" pairs for any flag in flagNames
+// from the front of raw, in any order, and returns their values alongside
+// whatever is left of raw once those flags are consumed. Unlike
+// tokenizeArgs, it never re-splits or rejoins that remainder, so a command
+// whose last argument is a free-form value (set's <value>) doesn't get
+// that value's internal whitespace collapsed to single spaces.
+func extractLeadingFlags(raw string, flagNames []string) (flags map[string]string, rest string) {
+	flags = map[string]string{}
+	rest = strings.TrimLeft(raw, " \t")
+
+	for {
+		token, after, ok := leadingToken(rest)
+		if !ok || !isFlagName(token, flagNames) {
+			return flags, rest
+		}
+
+		value, after, ok := leadingToken(strings.TrimLeft(after, " \t"))
+		if !ok {
+			return flags, rest
+		}
+
+		flags[token] = value
+		rest = strings.TrimLeft(after, " \t")
+	}
+}
+
+// leadingToken returns the first whitespace-delimited token at the front
+// of s and everything after it (whitespace included), or ok=false if s is
+// empty.
+func leadingToken(s string) (token, after string, ok bool) {
+	if s == "" {
+		return "", "", false
+	}
+
+	if end := strings.IndexAny(s, " \t"); end != -1 {
+		return s[:end], s[end:], true
+	}
+	return s, "", true
+}
+
+func isFlagName(token string, flagNames []string) bool {
+	for _, name := range flagNames {
+		if token == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const indexFileName = ".index.json"
+
+// entryMeta records the bookkeeping briefcase keeps about an entry
+// alongside its (possibly encrypted) value: when it was written and last
+// read, when it expires, its tags, and a hash of its value used to
+// detect tampering or staleness.
+type entryMeta struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	AccessedAt time.Time  `json:"accessed_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	SHA256     string     `json:"sha256"`
+}
+
+// expired reports whether meta's TTL has passed.
+func (m entryMeta) expired() bool {
+	return m.ExpiresAt != nil && time.Now().After(*m.ExpiresAt)
+}
+
+// hasTag reports whether meta is tagged with tag.
+func (m entryMeta) hasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// loadIndex reads the metadata index for the briefcase rooted at root. A
+// missing index is treated as empty rather than an error, since it simply
+// means no entry has been set with metadata yet.
+func loadIndex(root string) (map[string]entryMeta, error) {
+	data, err := os.ReadFile(path.Join(root, indexFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]entryMeta{}, nil
+		}
+		return nil, err
+	}
+
+	index := map[string]entryMeta{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// saveIndex persists the metadata index for the briefcase rooted at root.
+func saveIndex(root string, index map[string]entryMeta) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path.Join(root, indexFileName), data, 0600)
+}
+
+// recordSet updates name's metadata after a successful write: a fresh
+// content hash, a refreshed created-at time, its tags, and an optional
+// TTL-derived expiry.
+func recordSet(root, name, value string, ttl time.Duration, tags []string) error {
+	index, err := loadIndex(root)
+	if err != nil {
+		return err
+	}
+
+	meta := entryMeta{
+		CreatedAt:  time.Now(),
+		AccessedAt: time.Now(),
+		Tags:       tags,
+		SHA256:     hashValue(value),
+	}
+	if ttl > 0 {
+		expiresAt := meta.CreatedAt.Add(ttl)
+		meta.ExpiresAt = &expiresAt
+	}
+
+	index[name] = meta
+	return saveIndex(root, index)
+}
+
+// recordAccess updates name's last-access time, leaving everything else
+// untouched. A missing entry is not an error - every `set` records
+// metadata, but entries written before this existed, or brought in by
+// `import` (which writes values without metadata), have none.
+func recordAccess(root, name string) error {
+	index, err := loadIndex(root)
+	if err != nil {
+		return err
+	}
+
+	meta, ok := index[name]
+	if !ok {
+		return nil
+	}
+
+	meta.AccessedAt = time.Now()
+	index[name] = meta
+	return saveIndex(root, index)
+}
+
+// lookupMeta returns name's metadata, if any has been recorded.
+func lookupMeta(root, name string) (entryMeta, bool, error) {
+	index, err := loadIndex(root)
+	if err != nil {
+		return entryMeta{}, false, err
+	}
+
+	meta, ok := index[name]
+	return meta, ok, nil
+}
+
+// removeMeta deletes name's metadata entry, if any.
+func removeMeta(root, name string) error {
+	index, err := loadIndex(root)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := index[name]; !ok {
+		return nil
+	}
+
+	delete(index, name)
+	return saveIndex(root, index)
+}
+
+// purgeMeta deletes metadata for every entry under prefix ("" for all of
+// them), mirroring Store.Purge's namespace scoping.
+func purgeMeta(root, prefix string) error {
+	index, err := loadIndex(root)
+	if err != nil {
+		return err
+	}
+
+	if prefix != "" {
+		prefix = path.Clean(prefix)
+	}
+
+	for name := range index {
+		if prefix == "" || name == prefix || strings.HasPrefix(name, prefix+"/") {
+			delete(index, name)
+		}
+	}
+	return saveIndex(root, index)
+}
+
+// hashValue returns the hex-encoded SHA-256 of value, used to let readers
+// confirm a decrypted value matches what was written rather than silently
+// trusting it.
+func hashValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetWithTTLAndTags(t *testing.T) {
+	setVarsForTest(t)
+	args := UserArgs{"MyVar", "--ttl 1h --tag prod,aws data"}
+	if !set(args) {
+		t.Fatal("expected set to succeed")
+	}
+
+	meta, ok, err := lookupMeta(getBriefcaseDir(), "MyVar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("expected metadata to be recorded")
+	}
+	if meta.ExpiresAt == nil {
+		t.Fatal("expected an expiry to be recorded")
+	}
+	if !meta.hasTag("prod") || !meta.hasTag("aws") {
+		t.Fatal("expected both tags to be recorded, got ", meta.Tags)
+	}
+	if meta.SHA256 != hashValue("data") {
+		t.Fatal("expected the recorded hash to match the stored value")
+	}
+}
+
+func TestGetRefusesExpiredEntry(t *testing.T) {
+	setVarsForTest(t)
+	set(UserArgs{"MyVar", "--ttl 1ms data"})
+	time.Sleep(5 * time.Millisecond)
+
+	if get(UserArgs{"MyVar", ""}) {
+		t.Fatal("expected get to refuse an expired entry")
+	}
+
+	if _, err := getStore().Get("MyVar"); err == nil {
+		t.Fatal("expected the expired entry to have been removed from storage")
+	}
+}
+
+func TestGetRefusesTamperedEntry(t *testing.T) {
+	setVarsForTest(t)
+	t.Setenv("BRIEFCASE_PLAINTEXT", "1")
+	set(UserArgs{"MyVar", "data"})
+
+	// Overwrite the stored value directly, bypassing recordSet, so the
+	// index still holds the hash of "data" while the entry now reads
+	// "tampered".
+	if err := getStore().Set("MyVar", "tampered"); err != nil {
+		t.Fatal(err)
+	}
+
+	if get(UserArgs{"MyVar", ""}) {
+		t.Fatal("expected get to refuse an entry whose hash no longer matches")
+	}
+}
+
+func TestGCRemovesExpiredEntries(t *testing.T) {
+	setVarsForTest(t)
+	set(UserArgs{"Fresh", "data"})
+	set(UserArgs{"Stale", "--ttl 1ms data"})
+	time.Sleep(5 * time.Millisecond)
+
+	if !gc(UserArgs{}) {
+		t.Fatal("expected gc to succeed")
+	}
+
+	names, err := getStore().List("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "Fresh" {
+		t.Fatal("expected only Fresh to remain, got ", names)
+	}
+}
+
+func TestListFiltersByTag(t *testing.T) {
+	setVarsForTest(t)
+	set(UserArgs{"Prod", "--tag prod data"})
+	set(UserArgs{"Dev", "--tag dev data"})
+
+	out := stealStdOut(t)
+	list(UserArgs{"--tag", "prod"})
+	restoreStdOut(&out)
+	data := getStdOut(&out)
+
+	if strings.TrimSpace(data) != "Prod" {
+		t.Fatal("expected only the prod-tagged entry to be listed, got ", data)
+	}
+}
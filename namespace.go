@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sanitizeName validates a user-supplied entry or namespace name and
+// returns its cleaned, slash-delimited form. Names are rejected if they
+// are absolute, empty, or resolve outside of root - whether directly
+// (via "..") or indirectly through a symlink already present on disk.
+func sanitizeName(root, name string) (string, error) {
+	if name == "" {
+		return "", errors.New("name must not be empty")
+	}
+
+	if path.IsAbs(name) || filepath.IsAbs(name) {
+		return "", errors.New("name must not be an absolute path")
+	}
+
+	cleaned := path.Clean(name)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errors.New("name must not escape the briefcase root")
+	}
+
+	rootAbs, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	fullAbs, err := filepath.Abs(filepath.Join(rootAbs, filepath.FromSlash(cleaned)))
+	if err != nil {
+		return "", err
+	}
+
+	if fullAbs != rootAbs && !strings.HasPrefix(fullAbs, rootAbs+string(filepath.Separator)) {
+		return "", errors.New("name must not escape the briefcase root")
+	}
+
+	if resolvedRoot, err := filepath.EvalSymlinks(rootAbs); err == nil {
+		// Resolve the deepest part of the path that already exists (the
+		// entry itself, or its nearest existing ancestor).
+		for p := fullAbs; ; p = filepath.Dir(p) {
+			if resolved, err := filepath.EvalSymlinks(p); err == nil {
+				if resolved != resolvedRoot && !strings.HasPrefix(resolved, resolvedRoot+string(filepath.Separator)) {
+					return "", errors.New("name must not escape the briefcase root via a symlink")
+				}
+				break
+			}
+			if p == rootAbs || filepath.Dir(p) == p {
+				break
+			}
+		}
+	}
+
+	return cleaned, nil
+}
+
+// resolvePath validates name and returns the absolute filesystem path it
+// maps to under root. It does not create any directories.
+func resolvePath(root, name string) (string, error) {
+	cleaned, err := sanitizeName(root, name)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(root, filepath.FromSlash(cleaned)), nil
+}
+
+// isGlobPattern reports whether prefix should be treated as a glob (e.g.
+// "work/*/token") rather than a literal namespace path.
+func isGlobPattern(prefix string) bool {
+	return strings.ContainsAny(prefix, "*?[")
+}
+
+// listEntries lists the entries under prefix ("" for the root of root).
+// Glob prefixes are always matched recursively against the full relative
+// path of every entry; plain prefixes honor recursive. skip, if non-nil,
+// excludes files (e.g. internal bookkeeping files) by base name.
+func listEntries(root, prefix string, recursive bool, skip func(name string) bool) ([]string, error) {
+	if skip == nil {
+		skip = func(string) bool { return false }
+	}
+
+	if isGlobPattern(prefix) {
+		return globEntries(root, prefix, skip)
+	}
+
+	dir := root
+	if prefix != "" {
+		full, err := resolvePath(root, prefix)
+		if err != nil {
+			return nil, err
+		}
+		dir = full
+	}
+
+	if !recursive {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			if !file.IsDir() && skip(file.Name()) {
+				continue
+			}
+
+			name := file.Name()
+			if file.IsDir() {
+				name += "/"
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || skip(d.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// globEntries walks the whole store and returns every entry whose
+// root-relative path matches pattern.
+func globEntries(root, pattern string, skip func(name string) bool) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || skip(d.Name()) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		matched, err := path.Match(pattern, rel)
+		if err != nil {
+			return err
+		}
+		if matched {
+			names = append(names, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// purgeNamespace removes everything under prefix, or the whole store when
+// prefix is "".
+func purgeNamespace(root, prefix string) error {
+	if prefix == "" {
+		return os.RemoveAll(root)
+	}
+
+	full, err := resolvePath(root, prefix)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(full)
+}
@@ -0,0 +1,97 @@
+package main
+
+import "testing"
+
+func TestNamespacedSetGet(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	if err := store.Set("work/aws/token", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := store.Get("work/aws/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "secret" {
+		t.Fatal("Failed to round-trip namespaced entry, got ", value)
+	}
+}
+
+func TestSanitizeNameRejectsEscapes(t *testing.T) {
+	dir := setVarsForTest(t)
+	store := getStore()
+
+	for _, name := range []string{"../escape", "work/../../escape", "/etc/passwd", ""} {
+		if err := store.Set(name, "value"); err == nil {
+			t.Fatalf("expected %q to be rejected, but it was accepted under %s", name, dir)
+		}
+	}
+}
+
+func TestListRecursiveAndNamespace(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	store.Set("work/aws/token", "a")
+	store.Set("work/gcp/token", "b")
+	store.Set("personal/email", "c")
+
+	top, err := store.List("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 2 {
+		t.Fatal("expected 2 top-level entries (namespaces), got ", top)
+	}
+
+	ns, err := store.List("work", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 2 {
+		t.Fatal("expected 2 entries under work/, got ", ns)
+	}
+
+	all, err := store.List("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatal("expected 3 entries recursively, got ", all)
+	}
+}
+
+func TestListGlob(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	store.Set("work/aws/token", "a")
+	store.Set("work/gcp/token", "b")
+	store.Set("work/aws/region", "c")
+
+	matches, err := store.List("work/*/token", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 {
+		t.Fatal("expected glob to match 2 entries, got ", matches)
+	}
+}
+
+func TestPurgeNamespace(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	store.Set("work/aws/token", "a")
+	store.Set("personal/email", "b")
+
+	if err := store.Purge("work"); err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := store.List("", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0] != "personal/email" {
+		t.Fatal("expected only personal/email to remain, got ", remaining)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// env prints `export NAME=value` lines (or the fish/PowerShell equivalent)
+// for every briefcase entry, for use as `eval "$(briefcase env)"`. Flags
+// (in any order): --posix/--fish/--powershell to pick the output syntax,
+// --prefix <namespace> to scope to a namespace or glob (equivalent to
+// passing it as the bare trailing argument), and --only <a,b,c> to
+// restrict output to specific entry names.
+func env(args UserArgs) bool {
+	tokens := tokenizeArgs(args)
+
+	shell := "posix"
+	for _, s := range []string{"posix", "fish", "powershell"} {
+		if found, remaining := extractBoolFlag(tokens, "--"+s); found {
+			shell = s
+			tokens = remaining
+			break
+		}
+	}
+
+	onlyRaw, tokens, hasOnly := extractValueFlag(tokens, "--only")
+	prefixFlag, tokens, hasPrefix := extractValueFlag(tokens, "--prefix")
+
+	prefix := strings.Join(tokens, " ")
+	if hasPrefix {
+		prefix = prefixFlag
+	}
+
+	store := getStore()
+	names, err := store.List(prefix, true)
+	if err != nil {
+		fmt.Println("ERROR: failed to list entries - " + err.Error())
+		return false
+	}
+
+	if hasOnly {
+		names = filterOnly(names, strings.Split(onlyRaw, ","))
+	}
+
+	for _, name := range names {
+		value, err := store.Get(name)
+		if err != nil {
+			fmt.Println("ERROR: failed to read entry " + name + " - " + err.Error())
+			return false
+		}
+
+		line, err := formatExport(shell, envVarName(name), value)
+		if err != nil {
+			fmt.Println("ERROR: " + err.Error())
+			return false
+		}
+		fmt.Println(line)
+	}
+
+	return true
+}
+
+// filterOnly returns the subset of names present in allowed.
+func filterOnly(names []string, allowed []string) []string {
+	keep := make(map[string]bool, len(allowed))
+	for _, name := range allowed {
+		keep[name] = true
+	}
+
+	filtered := make([]string, 0, len(names))
+	for _, name := range names {
+		if keep[name] {
+			filtered = append(filtered, name)
+		}
+	}
+	return filtered
+}
+
+// execCmd loads every briefcase entry into the environment and runs a
+// child command with it. Its arguments come after a literal "--" in
+// os.Args rather than through UserArgs, since the command being run can
+// have its own flags and arguments of arbitrary shape.
+func execCmd(_ UserArgs) bool {
+	dashIndex := -1
+	for i, arg := range os.Args {
+		if arg == "--" {
+			dashIndex = i
+			break
+		}
+	}
+
+	if dashIndex == -1 || dashIndex+1 >= len(os.Args) {
+		fmt.Println("ERROR: exec requires a command after '--', e.g. briefcase exec -- env")
+		return false
+	}
+	cmdArgs := os.Args[dashIndex+1:]
+
+	store := getStore()
+	names, err := store.List("", true)
+	if err != nil {
+		fmt.Println("ERROR: failed to list entries - " + err.Error())
+		return false
+	}
+
+	overrides := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := store.Get(name)
+		if err != nil {
+			fmt.Println("ERROR: failed to read entry " + name + " - " + err.Error())
+			return false
+		}
+		overrides[envVarName(name)] = value
+	}
+
+	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+	cmd.Env = mergeEnv(os.Environ(), overrides)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Println("ERROR: failed to run command - " + err.Error())
+		return false
+	}
+
+	return true
+}
+
+// envVarName turns a (possibly namespaced) entry name into a valid
+// environment variable name: uppercased, with any character that isn't
+// alphanumeric (including the "/" namespace separator) replaced by "_".
+func envVarName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+
+	result := b.String()
+	if len(result) > 0 && result[0] >= '0' && result[0] <= '9' {
+		result = "_" + result
+	}
+	return result
+}
+
+// mergeEnv overlays overrides onto base (as produced by os.Environ()),
+// replacing an existing "NAME=value" pair in place if one is already
+// present and appending otherwise. This models exec.Cmd's own env-merging
+// semantics: on Windows, variable names are matched case-insensitively,
+// and a leading "=" is part of the name rather than the delimiter (as in
+// Windows' per-drive working directory variables like "=C:").
+func mergeEnv(base []string, overrides map[string]string) []string {
+	caseInsensitive := runtime.GOOS == "windows"
+
+	keyOf := func(kv string) (string, bool) {
+		if len(kv) == 0 {
+			return "", false
+		}
+		if eq := strings.IndexByte(kv[1:], '='); eq >= 0 {
+			return kv[:eq+1], true
+		}
+		return "", false
+	}
+
+	normalize := func(key string) string {
+		if caseInsensitive {
+			return strings.ToUpper(key)
+		}
+		return key
+	}
+
+	result := make([]string, len(base))
+	copy(result, base)
+
+	index := make(map[string]int, len(result))
+	for i, kv := range result {
+		if key, ok := keyOf(kv); ok {
+			index[normalize(key)] = i
+		}
+	}
+
+	for name, value := range overrides {
+		entry := name + "=" + value
+		if i, exists := index[normalize(name)]; exists {
+			result[i] = entry
+		} else {
+			index[normalize(name)] = len(result)
+			result = append(result, entry)
+		}
+	}
+
+	return result
+}
+
+// formatExport renders a NAME=value assignment in the given shell's
+// syntax, quoting value so it is always treated as a single literal
+// argument.
+func formatExport(shell, name, value string) (string, error) {
+	switch shell {
+	case "posix", "":
+		return "export " + name + "=" + quotePosix(value), nil
+	case "fish":
+		return "set -gx " + name + " " + quoteFish(value), nil
+	case "powershell":
+		return "$Env:" + name + " = " + quotePowerShell(value), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %q (expected posix, fish, or powershell)", shell)
+	}
+}
+
+// quotePosix wraps value in single quotes, ending/restarting the quoted
+// string around any embedded single quote ('\'' is how you get a literal
+// single quote inside POSIX single-quoting).
+func quotePosix(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// quoteFish wraps value in single quotes; inside fish single-quoted
+// strings only backslash and the quote character itself need escaping.
+func quoteFish(value string) string {
+	escaped := strings.ReplaceAll(value, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, "'", `\'`)
+	return "'" + escaped + "'"
+}
+
+// quotePowerShell wraps value in single quotes; inside PowerShell
+// single-quoted strings a literal single quote is written doubled.
+func quotePowerShell(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvVarName(t *testing.T) {
+	cases := map[string]string{
+		"work/aws/token": "WORK_AWS_TOKEN",
+		"MyVar":          "MYVAR",
+		"9lives":         "_9LIVES",
+	}
+
+	for in, want := range cases {
+		if got := envVarName(in); got != want {
+			t.Fatalf("envVarName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatExport(t *testing.T) {
+	cases := []struct {
+		shell string
+		want  string
+	}{
+		{"posix", `export NAME='it'\''s complicated'`},
+		{"fish", `set -gx NAME 'it\'s complicated'`},
+		{"powershell", `$Env:NAME = 'it''s complicated'`},
+	}
+
+	for _, c := range cases {
+		got, err := formatExport(c.shell, "NAME", "it's complicated")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Fatalf("formatExport(%q) = %q, want %q", c.shell, got, c.want)
+		}
+	}
+
+	if _, err := formatExport("bogus", "NAME", "value"); err == nil {
+		t.Fatal("expected an unsupported shell to be rejected")
+	}
+}
+
+func TestQuotePosixWrapsInSingleQuotes(t *testing.T) {
+	quoted := quotePosix("it's got a ' quote")
+	if !strings.HasPrefix(quoted, "'") || !strings.HasSuffix(quoted, "'") {
+		t.Fatalf("expected quotePosix output to be single-quoted, got %q", quoted)
+	}
+}
+
+func TestEnvPrefixAndOnlyFilters(t *testing.T) {
+	setVarsForTest(t)
+	set(UserArgs{"work/aws/token", "secret1"})
+	set(UserArgs{"other", "secret2"})
+
+	out := stealStdOut(t)
+	env(UserArgs{"--prefix", "work"})
+	restoreStdOut(&out)
+	data := getStdOut(&out)
+	if !strings.Contains(data, "WORK_AWS_TOKEN") || strings.Contains(data, "OTHER") {
+		t.Fatal("expected --prefix to scope to the work namespace, got ", data)
+	}
+
+	out = stealStdOut(t)
+	env(UserArgs{"--only", "other"})
+	restoreStdOut(&out)
+	data = getStdOut(&out)
+	if !strings.Contains(data, "OTHER") || strings.Contains(data, "WORK_AWS_TOKEN") {
+		t.Fatal("expected --only to restrict to the named entry, got ", data)
+	}
+}
+
+func TestMergeEnvOverridesAndAppends(t *testing.T) {
+	base := []string{"PATH=/usr/bin", "HOME=/home/user"}
+	merged := mergeEnv(base, map[string]string{"HOME": "/home/other", "TOKEN": "secret"})
+
+	found := map[string]string{}
+	for _, kv := range merged {
+		parts := strings.SplitN(kv, "=", 2)
+		found[parts[0]] = parts[1]
+	}
+
+	if found["HOME"] != "/home/other" {
+		t.Fatal("expected HOME to be overridden, got ", found["HOME"])
+	}
+	if found["PATH"] != "/usr/bin" {
+		t.Fatal("expected PATH to be preserved, got ", found["PATH"])
+	}
+	if found["TOKEN"] != "secret" {
+		t.Fatal("expected TOKEN to be appended, got ", found["TOKEN"])
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltFileName = ".salt"
+	kdfKeyLength = 32
+	// Argon2id parameters, per the OWASP baseline recommendation (19 MiB
+	// would be the minimum; 64 MiB buys more resistance to GPU/ASIC
+	// cracking while still running in well under a second).
+	argon2Time     = 1
+	argon2Memory   = 64 * 1024
+	argon2Threads  = 4
+	nonceLength    = 12
+	keyringService = "briefcase"
+)
+
+// isReservedFile reports whether name is one of briefcase's own
+// bookkeeping files rather than a user entry, so List can hide it.
+func isReservedFile(name string) bool {
+	return name == saltFileName || name == indexFileName
+}
+
+// Store is the interface commands use to persist and retrieve briefcase
+// entries. It intentionally mirrors the shape of the original filesystem
+// calls in briefcase.go so FileStore and EncryptedStore are drop-in
+// replacements for each other. name and prefix are slash-delimited
+// namespace paths, e.g. "work/aws/token".
+type Store interface {
+	Set(name, value string) error
+	Get(name string) (string, error)
+	Remove(name string) error
+	// List returns entries under prefix ("" for the root). Non-recursive
+	// listings include immediate child namespaces with a trailing "/".
+	List(prefix string, recursive bool) ([]string, error)
+	// Purge deletes everything under prefix, or the whole briefcase when
+	// prefix is "".
+	Purge(prefix string) error
+}
+
+// getStore returns the Store implementation briefcase should use. Encrypted
+// storage is the default; BRIEFCASE_PLAINTEXT opts back into the original
+// unencrypted FileStore behavior.
+func getStore() Store {
+	dir := getBriefcaseDir()
+	if os.Getenv("BRIEFCASE_PLAINTEXT") != "" {
+		return &FileStore{dir: dir}
+	}
+	return &EncryptedStore{dir: dir}
+}
+
+// FileStore is the original plaintext-on-disk storage backend.
+type FileStore struct {
+	dir string
+}
+
+func (s *FileStore) Set(name, value string) error {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(full, []byte(value), 0644)
+}
+
+func (s *FileStore) Get(name string) (string, error) {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (s *FileStore) Remove(name string) error {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(full)
+}
+
+func (s *FileStore) List(prefix string, recursive bool) ([]string, error) {
+	return listEntries(s.dir, prefix, recursive, isReservedFile)
+}
+
+func (s *FileStore) Purge(prefix string) error {
+	return purgeNamespace(s.dir, prefix)
+}
+
+// EncryptedStore stores each entry's value as AES-GCM ciphertext, keyed by a
+// passphrase-derived key. Entry names are kept as plaintext filenames so
+// List can still enumerate entries without decrypting anything.
+type EncryptedStore struct {
+	dir string
+}
+
+func (s *EncryptedStore) Set(name, value string) error {
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	return s.setWithCipher(name, value, gcm)
+}
+
+// setWithCipher writes name/value using an already-built cipher, so callers
+// that need to encrypt under a passphrase other than the active one (e.g.
+// rekeyed, mid-rotation) don't have to go through getPassphrase.
+func (s *EncryptedStore) setWithCipher(name, value string, gcm cipher.AEAD) error {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+		return err
+	}
+
+	nonce := make([]byte, nonceLength)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(value), nil)
+	return os.WriteFile(full, ciphertext, 0600)
+}
+
+func (s *EncryptedStore) Get(name string) (string, error) {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < nonceLength {
+		return "", errors.New("entry is corrupt or not encrypted")
+	}
+
+	nonce, ciphertext := data[:nonceLength], data[nonceLength:]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt entry - wrong passphrase or corrupt data")
+	}
+
+	return string(plaintext), nil
+}
+
+func (s *EncryptedStore) Remove(name string) error {
+	full, err := resolvePath(s.dir, name)
+	if err != nil {
+		return err
+	}
+	return os.Remove(full)
+}
+
+func (s *EncryptedStore) List(prefix string, recursive bool) ([]string, error) {
+	return listEntries(s.dir, prefix, recursive, isReservedFile)
+}
+
+func (s *EncryptedStore) Purge(prefix string) error {
+	return purgeNamespace(s.dir, prefix)
+}
+
+// cipher builds the AES-GCM cipher for this store using the active
+// passphrase and the per-briefcase salt, deriving a fresh key each call
+// rather than caching one in memory.
+func (s *EncryptedStore) cipher() (cipher.AEAD, error) {
+	passphrase, err := getPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return s.cipherFor(passphrase)
+}
+
+// cipherFor builds the AES-GCM cipher for this store using an explicit
+// passphrase rather than the one getPassphrase resolves, so rekeyed can
+// encrypt under the new passphrase before it's been persisted anywhere.
+func (s *EncryptedStore) cipherFor(passphrase string) (cipher.AEAD, error) {
+	salt, err := s.salt()
+	if err != nil {
+		return nil, err
+	}
+
+	key := deriveKey(passphrase, salt)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// salt returns this briefcase's salt, creating and persisting a new random
+// one on first use.
+func (s *EncryptedStore) salt() ([]byte, error) {
+	saltPath := path.Join(s.dir, saltFileName)
+
+	if data, err := os.ReadFile(saltPath); err == nil {
+		return data, nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(saltPath, salt, 0600); err != nil {
+		return nil, err
+	}
+
+	return salt, nil
+}
+
+// rekeyed re-encrypts every entry under a freshly generated salt using
+// newPassphrase. All entries are decrypted first; the new salt is then
+// written to disk, replacing the current one, before any entry is
+// rewritten under it.
+func (s *EncryptedStore) rekeyed(newPassphrase string) error {
+	names, err := s.List("", true)
+	if err != nil {
+		return err
+	}
+
+	plaintext := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := s.Get(name)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %q during rekey: %w", name, err)
+		}
+		plaintext[name] = value
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path.Join(s.dir, saltFileName), salt, 0600); err != nil {
+		return err
+	}
+
+	gcm, err := s.cipherFor(newPassphrase)
+	if err != nil {
+		return fmt.Errorf("failed to derive the new key during rekey: %w", err)
+	}
+
+	for name, value := range plaintext {
+		if err := s.setWithCipher(name, value, gcm); err != nil {
+			return fmt.Errorf("failed to re-encrypt %q during rekey: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// deriveKey turns a passphrase and salt into an AES-256 key via Argon2id,
+// a memory-hard KDF that keeps brute-forcing a weak passphrase offline
+// expensive even for an attacker with GPUs/ASICs - unlike a plain
+// HMAC-based KDF, which is cheap to parallelize in hardware.
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, argon2Time, argon2Memory, argon2Threads, kdfKeyLength)
+}
+
+// getPassphrase resolves the passphrase used to derive the encryption key,
+// checking BRIEFCASE_PASSPHRASE, then BRIEFCASE_PASSPHRASE_FILE, then the
+// OS keyring (if BRIEFCASE_USE_KEYRING is set), and finally falling back to
+// an interactive prompt.
+func getPassphrase() (string, error) {
+	if passphrase := os.Getenv("BRIEFCASE_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+
+	if file := os.Getenv("BRIEFCASE_PASSPHRASE_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	useKeyring := os.Getenv("BRIEFCASE_USE_KEYRING") != ""
+	account := getBriefcaseDirName()
+
+	if useKeyring {
+		if passphrase, err := keyringGet(account); err == nil && passphrase != "" {
+			return passphrase, nil
+		}
+	}
+
+	fmt.Print("Briefcase passphrase: ")
+	reader := bufio.NewReader(os.Stdin)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	passphrase := strings.TrimRight(line, "\r\n")
+
+	if useKeyring {
+		_ = keyringSet(account, passphrase)
+	}
+
+	return passphrase, nil
+}
+
+// persistPassphrase updates whichever passphrase source rekey's caller is
+// using, so a future invocation can still decrypt the briefcase. It
+// follows the same precedence getPassphrase reads in: a BRIEFCASE_PASSPHRASE
+// env var can't be rewritten from inside this process, so that case (and
+// the interactive-prompt fallback) just gets clear instructions instead.
+func persistPassphrase(newPassphrase string) string {
+	if os.Getenv("BRIEFCASE_PASSPHRASE") != "" {
+		return "Run 'export BRIEFCASE_PASSPHRASE=" + newPassphrase + "' before your next briefcase command - rekey only updated the passphrase for this process"
+	}
+
+	if file := os.Getenv("BRIEFCASE_PASSPHRASE_FILE"); file != "" {
+		if err := os.WriteFile(file, []byte(newPassphrase+"\n"), 0600); err != nil {
+			return "WARNING: failed to update passphrase file " + file + " - " + err.Error() + "; run 'export BRIEFCASE_PASSPHRASE=" + newPassphrase + "' before your next briefcase command"
+		}
+		return "Updated the passphrase file at " + file
+	}
+
+	if os.Getenv("BRIEFCASE_USE_KEYRING") != "" {
+		account := getBriefcaseDirName()
+		if err := keyringSet(account, newPassphrase); err != nil {
+			return "WARNING: failed to update the OS keyring - " + err.Error() + "; run 'export BRIEFCASE_PASSPHRASE=" + newPassphrase + "' before your next briefcase command"
+		}
+		return "Updated the OS keyring entry"
+	}
+
+	return "Run 'export BRIEFCASE_PASSPHRASE=" + newPassphrase + "' before your next briefcase command - rekey only updated the passphrase for this process"
+}
+
+// keyringGet reads a passphrase from the OS keyring by shelling out to the
+// platform's native credential tool, avoiding a dependency on an external
+// keyring module.
+func keyringGet(account string) (string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("security", "find-generic-password", "-a", account, "-s", keyringService, "-w").Output()
+		return strings.TrimRight(string(out), "\n"), err
+	case "linux":
+		out, err := exec.Command("secret-tool", "lookup", "service", keyringService, "account", account).Output()
+		return strings.TrimRight(string(out), "\n"), err
+	default:
+		return "", fmt.Errorf("keyring not supported on %s", runtime.GOOS)
+	}
+}
+
+// keyringSet stores a passphrase in the OS keyring using the same
+// platform-native tools as keyringGet.
+func keyringSet(account, passphrase string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("security", "add-generic-password", "-a", account, "-s", keyringService, "-w", passphrase, "-U").Run()
+	case "linux":
+		cmd := exec.Command("secret-tool", "store", "--label", keyringService, "service", keyringService, "account", account)
+		cmd.Stdin = strings.NewReader(passphrase)
+		return cmd.Run()
+	default:
+		return fmt.Errorf("keyring not supported on %s", runtime.GOOS)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	if _, ok := store.(*EncryptedStore); !ok {
+		t.Fatal("expected EncryptedStore to be the default store")
+	}
+
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := store.Get("MyVar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "data" {
+		t.Fatal("Failed to round-trip encrypted data, got ", data)
+	}
+}
+
+func TestEncryptedStoreOnDiskIsNotPlaintext(t *testing.T) {
+	dir := setVarsForTest(t)
+	store := getStore()
+	if err := store.Set("MyVar", "super-secret-value"); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(dir + "/" + DIRNAME + "/MyVar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) == "super-secret-value" {
+		t.Fatal("entry was stored as plaintext on disk")
+	}
+}
+
+func TestEncryptedStoreWrongPassphraseFails(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore()
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BRIEFCASE_PASSPHRASE", "a-different-passphrase")
+	if _, err := store.Get("MyVar"); err == nil {
+		t.Fatal("expected decryption with the wrong passphrase to fail")
+	}
+}
+
+func TestFileStoreOptIn(t *testing.T) {
+	dir := setVarsForTest(t)
+	t.Setenv("BRIEFCASE_PLAINTEXT", "1")
+	store := getStore()
+	if _, ok := store.(*FileStore); !ok {
+		t.Fatal("expected BRIEFCASE_PLAINTEXT to select FileStore")
+	}
+
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := os.ReadFile(dir + "/" + DIRNAME + "/MyVar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(raw) != "data" {
+		t.Fatal("expected FileStore to write plaintext, got ", string(raw))
+	}
+}
+
+func TestRekey(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore().(*EncryptedStore)
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.rekeyed("a-new-passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	// rekeyed only re-encrypts on disk; it's the caller's job (via
+	// persistPassphrase) to make sure the new passphrase is what gets
+	// resolved on the next read.
+	t.Setenv("BRIEFCASE_PASSPHRASE", "a-new-passphrase")
+
+	data, err := store.Get("MyVar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "data" {
+		t.Fatal("Failed to read entry after rekey, got ", data)
+	}
+}
+
+func TestRekeyInvalidatesOldPassphrase(t *testing.T) {
+	setVarsForTest(t)
+	store := getStore().(*EncryptedStore)
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.rekeyed("a-new-passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("MyVar"); err == nil {
+		t.Fatal("expected the old passphrase to no longer decrypt entries after rekey")
+	}
+}
+
+func TestRekeyUpdatesPassphraseFile(t *testing.T) {
+	setVarsForTest(t)
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("test-passphrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BRIEFCASE_PASSPHRASE", "")
+	t.Setenv("BRIEFCASE_PASSPHRASE_FILE", passphraseFile)
+
+	store := getStore().(*EncryptedStore)
+	if err := store.Set("MyVar", "data"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.rekeyed("a-new-passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := persistPassphrase("a-new-passphrase")
+	if !strings.Contains(msg, passphraseFile) {
+		t.Fatal("expected persistPassphrase to report updating the passphrase file, got ", msg)
+	}
+
+	updated, err := os.ReadFile(passphraseFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(updated)) != "a-new-passphrase" {
+		t.Fatal("expected the passphrase file to hold the new passphrase, got ", string(updated))
+	}
+}
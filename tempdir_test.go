@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestResolveTempDirWindowsPrecedence(t *testing.T) {
+	t.Setenv("TMP", "")
+	t.Setenv("TEMP", "")
+	t.Setenv("USERPROFILE", "")
+	t.Setenv("TMPDIR", "")
+
+	t.Setenv("USERPROFILE", `C:\Users\test`)
+	if got := resolveTempDir("windows"); got.path != `C:\Users\test` || got.envVar != "USERPROFILE" {
+		t.Fatalf("expected USERPROFILE fallback, got %+v", got)
+	}
+
+	t.Setenv("TEMP", `C:\Temp`)
+	if got := resolveTempDir("windows"); got.path != `C:\Temp` || got.envVar != "TEMP" {
+		t.Fatalf("expected TEMP to take precedence over USERPROFILE, got %+v", got)
+	}
+
+	t.Setenv("TMP", `C:\Tmp`)
+	if got := resolveTempDir("windows"); got.path != `C:\Tmp` || got.envVar != "TMP" {
+		t.Fatalf("expected TMP to take precedence over TEMP, got %+v", got)
+	}
+}
+
+func TestResolveTempDirUnixPrecedence(t *testing.T) {
+	for _, goos := range []string{"darwin", "linux"} {
+		t.Setenv("TMPDIR", "")
+		if got := resolveTempDir(goos); got.envVar != "TMPDIR" && got.envVar != "os.TempDir()" {
+			t.Fatalf("%s: expected TMPDIR or os.TempDir() source, got %+v", goos, got)
+		}
+
+		t.Setenv("TMPDIR", "/custom/tmp")
+		if got := resolveTempDir(goos); got.path != "/custom/tmp" || got.envVar != "TMPDIR" {
+			t.Fatalf("%s: expected TMPDIR to be honored, got %+v", goos, got)
+		}
+	}
+}
+
+func TestGetTempDirBriefcaseDirAlwaysWins(t *testing.T) {
+	t.Setenv("BRIEFCASE_DIR", "/explicit/dir")
+	t.Setenv("BRIEFCASE_PERSISTENT", "1")
+
+	if got := getTempDir(); got.path != "/explicit/dir" || got.envVar != "BRIEFCASE_DIR" {
+		t.Fatalf("expected BRIEFCASE_DIR to win over persistent mode, got %+v", got)
+	}
+}
+
+func TestGetTempDirPersistentUsesUserCacheDir(t *testing.T) {
+	t.Setenv("BRIEFCASE_DIR", "")
+	t.Setenv("BRIEFCASE_PERSISTENT", "1")
+	t.Setenv("XDG_CACHE_HOME", "/cache/dir")
+
+	got := getTempDir()
+	if got.envVar != "os.UserCacheDir()" || got.path != "/cache/dir" {
+		t.Fatalf("expected persistent mode to use os.UserCacheDir(), got %+v", got)
+	}
+}